@@ -0,0 +1,64 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// listDeadLettersHandler returns the current contents of the dead letter queue
+func (r *BridgeAPI) listDeadLettersHandler(w http.ResponseWriter, req *http.Request) {
+	entries, err := r.bridge.DeadLetters().List()
+	if err != nil {
+		log.Errorf("Failed to list the dead letter queue, error: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// replayDeadLetterHandler resubmits a dead lettered event to its subscriber and,
+// on success, removes it from the queue
+func (r *BridgeAPI) replayDeadLetterHandler(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	if err := r.bridge.Replay(id); err != nil {
+		log.Errorf("Failed to replay the dead letter entry: %s, error: %s", id, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// dropDeadLetterHandler discards a dead lettered event without attempting redelivery
+func (r *BridgeAPI) dropDeadLetterHandler(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	if err := r.bridge.Drop(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Errorf("Failed to encode the response, error: %s", err)
+	}
+}
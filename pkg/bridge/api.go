@@ -0,0 +1,84 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// BridgeAPI exposes the bridge over a http interface for operators: the
+// dead letter queue admin endpoints, read-only listing of subscriptions and
+// the Prometheus metrics. It has no routes for forwarding hooks or for
+// adding and removing subscriptions
+type BridgeAPI struct {
+	// the configuration
+	config *Config
+	// the bridge we are fronting
+	bridge Bridge
+	// the underlying router
+	router *mux.Router
+	// the listener the api is bound to
+	listener net.Listener
+}
+
+// NewBridgeAPI creates and starts the http api for the bridge
+//	cfg:		the bridge configuration
+//	bridge:		the bridge the api is fronting
+func NewBridgeAPI(cfg *Config, bridge Bridge) (*BridgeAPI, error) {
+	api := &BridgeAPI{
+		config: cfg,
+		bridge: bridge,
+		router: mux.NewRouter(),
+	}
+	api.addRoutes()
+
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		log.Errorf("Failed to bind the bridge api to: %s, error: %s", cfg.Listen, err)
+		return nil, err
+	}
+	api.listener = listener
+
+	go func() {
+		if err := http.Serve(listener, api.router); err != nil {
+			log.Errorf("The bridge api listener has exited, error: %s", err)
+		}
+	}()
+
+	return api, nil
+}
+
+// addRoutes wires up the http routes exposed by the bridge
+func (r *BridgeAPI) addRoutes() {
+	r.router.HandleFunc("/admin/dlq", r.listDeadLettersHandler).Methods("GET")
+	r.router.HandleFunc("/admin/dlq/{id}/replay", r.replayDeadLetterHandler).Methods("POST")
+	r.router.HandleFunc("/admin/dlq/{id}", r.dropDeadLetterHandler).Methods("DELETE")
+	r.router.HandleFunc("/subscriptions", r.listSubscriptionsHandler).Methods("GET")
+	r.router.HandleFunc("/subscriptions/{id}", r.getSubscriptionHandler).Methods("GET")
+	r.router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+}
+
+// Close shuts down the bridge api listener
+func (r *BridgeAPI) Close() error {
+	if r.listener == nil {
+		return nil
+	}
+	return r.listener.Close()
+}
@@ -14,36 +14,41 @@ limitations under the License.
 package bridge
 
 import (
-	"bytes"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
-	"io/ioutil"
-	"math/rand"
 	"net/http"
-	"os"
-	"regexp"
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/gambol99/bridge.io/pkg/bridge/client"
+	"github.com/gambol99/bridge.io/pkg/bridge/cloudevents"
+	"github.com/gambol99/bridge.io/pkg/bridge/delivery"
+	"github.com/gambol99/bridge.io/pkg/bridge/metrics"
+	"github.com/gambol99/bridge.io/pkg/bridge/store"
 
 	log "github.com/Sirupsen/logrus"
 )
 
 const (
-	SUBSCRIPTION_ID_LENGTH = 32
+	// SUBSCRIPTION_ID_BYTES is the amount of entropy read from crypto/rand
+	// to generate a subscription id
+	SUBSCRIPTION_ID_BYTES = 24
 )
 
+// the length, in characters, of a generated subscription id
+var subscriptionIDLength = base64.RawURLEncoding.EncodedLen(SUBSCRIPTION_ID_BYTES)
+
 // the bridge implementation
 type BridgeImpl struct {
-	sync.RWMutex
 	// the configuration
 	config *Config
-	// the subscriptions
-	subscriptions []*client.Subscription
+	// the subscription store
+	store store.Store
 	// the bridge api server
 	api *BridgeAPI
-	// the client used to connecting to the subscribers
-	client *http.Client
+	// the delivery manager, responsible for retries, backoff and the dead letter queue
+	delivery *delivery.Manager
 }
 
 // Create a new Bridge from the configuration
@@ -51,10 +56,31 @@ type BridgeImpl struct {
 func NewBridge(cfg *Config) (Bridge, error) {
 	var err error
 	bridge := &BridgeImpl{
-		config:        cfg,
-		subscriptions: make([]*client.Subscription, 0),
+		config: cfg,
+		store:  cfg.Store,
+	}
+	if bridge.store == nil {
+		bridge.store = store.NewMemoryStore()
+	}
+
+	// step: rehydrate and report on the subscriptions already held by the store
+	existing, err := bridge.store.List()
+	if err != nil {
+		log.Errorf("Failed to rehydrate subscriptions from the store, error: %s", err)
+		return nil, err
+	}
+	log.Infof("Rehydrated %d subscriptions from the store", len(existing))
+
+	if cfg.Delivery == nil {
+		cfg.Delivery = delivery.DefaultConfig()
+	}
+	if cfg.RequestTimeout > 0 {
+		cfg.Delivery.Timeout = cfg.RequestTimeout
+	}
+	if bridge.delivery, err = delivery.NewManager(cfg.Delivery); err != nil {
+		log.Errorf("Failed to create the delivery manager, error: %s", err)
+		return nil, err
 	}
-	bridge.client = &http.Client{}
 
 	// step: create an bridge api
 	if bridge.api, err = NewBridgeAPI(cfg, bridge); err != nil {
@@ -67,21 +93,36 @@ func NewBridge(cfg *Config) (Bridge, error) {
 
 // Close and release any resource being used by the bride
 func (b *BridgeImpl) Close() error {
-
-	return nil
+	var lastErr error
+	if err := b.delivery.Close(); err != nil {
+		log.Errorf("Failed to close the delivery manager, error: %s", err)
+		lastErr = err
+	}
+	if err := b.api.Close(); err != nil {
+		log.Errorf("Failed to close the bridge api, error: %s", err)
+		lastErr = err
+	}
+	if err := b.store.Close(); err != nil {
+		log.Errorf("Failed to close the subscription store, error: %s", err)
+		lastErr = err
+	}
+	return lastErr
 }
 
 func (b *BridgeImpl) Add(subscription *client.Subscription) (string, error) {
 	log.Infof("Attempting to add the subscription: %s", subscription)
 	// step: validate the hook
 	if err := subscription.Valid(); err != nil {
-		log.Errorf("Invalid subscription request: %V, error: %s", err)
+		log.Errorf("Invalid subscription request, error: %s", err)
 		return "", err
 	}
-	b.Lock()
-	defer b.Unlock()
 	subscription.SubscriptionID = b.generateSubscriptionID()
-	b.subscriptions = append(b.subscriptions, subscription)
+	subscription.CreatedAt = time.Now()
+	if err := b.store.Add(subscription); err != nil {
+		log.Errorf("Failed to persist the subscription, error: %s", err)
+		return "", err
+	}
+	b.syncSubscribersTotal()
 	return subscription.SubscriptionID, nil
 }
 
@@ -89,26 +130,27 @@ func (b *BridgeImpl) Add(subscription *client.Subscription) (string, error) {
 // 	id:			the subscription id which was given on subscribe()
 func (b *BridgeImpl) Remove(id string) error {
 	log.Infof("Attempting to remove the subscription id: %s", id)
-	if id == "" || len(id) < SUBSCRIPTION_ID_LENGTH {
+	if id == "" || len(id) != subscriptionIDLength {
 		return fmt.Errorf("Invalid subscription id, please check")
 	}
 
-	b.Lock()
-	defer b.Unlock()
-	sub_index := -1
-	for index, subscription := range b.subscriptions {
-		if subscription.SubscriptionID == id {
-			sub_index = index
-			break
-		}
+	if err := b.store.Remove(id); err != nil {
+		return err
 	}
+	b.delivery.RemoveQueue(id)
+	b.syncSubscribersTotal()
+	return nil
+}
 
-	if sub_index < 0 {
-		return fmt.Errorf("The subscription id: %s does not exists", id)
+// syncSubscribersTotal recomputes and publishes the registered subscription
+// count metric, called whenever a subscription is added or removed
+func (b *BridgeImpl) syncSubscribersTotal() {
+	subscriptions, err := b.store.List()
+	if err != nil {
+		log.Errorf("Failed to list the subscriptions to update the subscribers metric, error: %s", err)
+		return
 	}
-
-	b.subscriptions = append(b.subscriptions[:sub_index], b.subscriptions[sub_index+1])
-	return nil
+	metrics.SetSubscribersTotal(len(subscriptions))
 }
 
 // Called on a prehook event, i.e. when a client *first* makes a request to the API, but *before*
@@ -117,44 +159,130 @@ func (b *BridgeImpl) Remove(id string) error {
 //	request:	the content of the request
 func (b *BridgeImpl) PreHookEvent(uri string, request []byte) ([]byte, error) {
 	log.Infof("Bridge recieved a pre hook request, uri: %s", uri)
+	started := time.Now()
+	result := "ok"
+	defer func() {
+		metrics.ObserveHookEvent(client.PRE_EVENT, result, time.Since(started))
+	}()
+
 	forwarders := b.getListeners(uri, client.PRE_EVENT)
 	if len(forwarders) <= 0 {
 		log.Infof("Found %d subscribers listening out for: %s", len(forwarders), uri)
 		return request, nil
 	}
-	// step: we call each of the subscribers in turn
-	api_request := new(client.APIRequest)
-	api_request.ID, _ = os.Hostname()
-	api_request.Stamp = time.Now()
-	api_request.HookType = client.PRE_EVENT
-	api_request.Request = string(request)
-	api_request.URI = uri
-
+	// step: we call each of the subscribers in turn, synchronously, so a
+	// mutation made by one subscriber is visible to the next. Each call
+	// still goes through the delivery manager's retry and backoff, it's
+	// only the async queueing which is skipped for pre-hooks
 	for _, listener := range forwarders {
 		log.Debugf("Forwarding the request uri: %s to subscriber: %s", uri, listener.Endpoint)
-		rq, err := http.NewRequest("POST", listener.Endpoint, bytes.NewBuffer(request))
+		job, err := b.buildJob(listener, client.PRE_EVENT, uri, request)
 		if err != nil {
-			log.Errorf("Failed to construct a request for endpoint: %s, error: %s", listener.Endpoint, err)
+			log.Errorf("Failed to build the delivery for subscriber: %s, error: %s", listener.Endpoint, err)
+			result = "error"
 			continue
 		}
-		// step: perform the request
-		response, err := b.client.Do(rq)
+		content, headers, err := b.delivery.Send(job)
 		if err != nil {
 			log.Errorf("Failed to call the subscriber: %s, error: %s", listener.Endpoint, err)
+			result = "error"
+			continue
+		}
+		if listener.Secret != "" && !verifyResponse(listener.Secret, headers, content) {
+			log.Errorf("Rejecting mutation from subscriber: %s, missing or invalid signature", listener.Endpoint)
+			result = "error"
+			continue
+		}
+		if listener.DeliveryFormat() == client.FORMAT_LEGACY {
+			request = content
 			continue
 		}
-		// step: read in the response from the client
-		content, err := ioutil.ReadAll(response.Body)
+		mutated, err := cloudevents.ParseResponse(headers, content)
 		if err != nil {
-			log.Errorf("Failed to read the response boxy from subscriber: %s, error: %s", listener.Endpoint, err)
+			log.Errorf("Failed to parse the cloudevents response from subscriber: %s, error: %s", listener.Endpoint, err)
+			result = "error"
 			continue
 		}
+		request = mutated
+	}
 
-		request = content
+	return request, nil
+}
 
+// buildJob constructs the delivery.Job for a listener, wrapping the request
+// in a CloudEvents envelope unless the subscription has opted into the
+// legacy wire format
+func (b *BridgeImpl) buildJob(listener *client.Subscription, hookType, uri string, body []byte) (*delivery.Job, error) {
+	job := &delivery.Job{
+		SubscriptionID: listener.SubscriptionID,
+		Endpoint:       listener.Endpoint,
+		HookType:       hookType,
+		URI:            uri,
 	}
 
-	return request, nil
+	format := listener.DeliveryFormat()
+	if format == client.FORMAT_LEGACY {
+		job.Body = body
+		signJob(listener, job)
+		return job, nil
+	}
+
+	source := cloudevents.Source(b.config.Name)
+	var event *cloudevents.Event
+	if hookType == client.PRE_EVENT {
+		event = cloudevents.NewPreHookEvent(source, uri, body)
+	} else {
+		event = cloudevents.NewPostHookEvent(source, uri, body)
+	}
+
+	var (
+		payload []byte
+		headers http.Header
+		err     error
+	)
+	if format == cloudevents.ModeBinary {
+		payload, headers, err = event.BinaryRequest()
+	} else {
+		payload, headers, err = event.StructuredRequest()
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.Body = payload
+	job.Headers = headers
+	signJob(listener, job)
+	return job, nil
+}
+
+// signJob attaches an HMAC-SHA256 signature of the job body to its
+// headers when the subscription has a shared secret configured, so the
+// subscriber can verify the delivery actually came from this bridge
+func signJob(listener *client.Subscription, job *delivery.Job) {
+	if listener.Secret == "" {
+		return
+	}
+	if job.Headers == nil {
+		job.Headers = make(http.Header)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	job.Headers.Set(client.TimestampHeader, timestamp)
+	job.Headers.Set(client.SubscriptionIDHeader, listener.SubscriptionID)
+	job.Headers.Set(client.SignatureHeader, client.Sign(listener.Secret, timestamp, job.Body))
+}
+
+// verifyResponse checks a subscriber's response on a pre hook mutation
+// carries a valid signature over its body, proving the mutation actually
+// came back from the subscriber it was sent to
+func verifyResponse(secret string, headers http.Header, body []byte) bool {
+	if headers == nil {
+		return false
+	}
+	signature := headers.Get(client.SignatureHeader)
+	timestamp := headers.Get(client.TimestampHeader)
+	if signature == "" || timestamp == "" {
+		return false
+	}
+	return client.Verify(secret, timestamp, body, signature)
 }
 
 // Called on a posthook event, i.e. the response from the sink
@@ -162,46 +290,97 @@ func (b *BridgeImpl) PreHookEvent(uri string, request []byte) ([]byte, error) {
 //	request:	the content of the request
 func (b *BridgeImpl) PostHookEvent(uri string, request []byte) ([]byte, error) {
 	log.Infof("Bridge recieved a post hook request, uri: %s", uri)
+	started := time.Now()
+	result := "ok"
+	defer func() {
+		metrics.ObserveHookEvent(client.POST_EVENT, result, time.Since(started))
+	}()
+
 	forwarders := b.getListeners(uri, client.POST_EVENT)
 	if len(forwarders) <= 0 {
 		log.Infof("Found %d subscribers listening out for: %s", len(forwarders), uri)
 		return request, nil
 	}
+	// step: post-hooks are fire and forget, we enqueue the event onto the
+	// subscriber's delivery queue and return immediately
+	for _, listener := range forwarders {
+		log.Debugf("Enqueuing the request uri: %s for subscriber: %s", uri, listener.Endpoint)
+		job, err := b.buildJob(listener, client.POST_EVENT, uri, request)
+		if err != nil {
+			log.Errorf("Failed to build the delivery for subscriber: %s, error: %s", listener.Endpoint, err)
+			result = "error"
+			continue
+		}
+		b.delivery.Enqueue(job)
+	}
 
 	return request, nil
 }
 
 // Retrieve the current subscriptions which are in the bridge
 func (b *BridgeImpl) Subscriptions() []*client.Subscription {
-	b.RLock()
-	defer b.RUnlock()
-	return b.subscriptions
+	subscriptions, err := b.store.List()
+	if err != nil {
+		log.Errorf("Failed to list the subscriptions, error: %s", err)
+		return nil
+	}
+	return subscriptions
 }
 
-func (b *BridgeImpl) getListeners(uri, hook_type string) []*client.Subscription {
-	b.RLock()
-	defer b.RUnlock()
-	forwarders := make([]*client.Subscription, 0)
-	// step: we build a list of subscribers for this uri
-	for _, subscription := range b.subscriptions {
-		for _, hook := range subscription.Requests {
-			if hook.HookType == hook_type {
-				if matched, err := regexp.MatchString(hook.URI, uri); err != nil {
-					log.Errorf("The regex for the hook: %s is invalid, error: %s", err)
-				} else if matched {
-					forwarders = append(forwarders, subscription)
-				}
-			}
-		}
+// Get returns a single subscription by id
+func (b *BridgeImpl) Get(id string) (*client.Subscription, error) {
+	return b.store.Get(id)
+}
+
+// DeadLetters exposes the dead letter store behind the delivery manager
+func (b *BridgeImpl) DeadLetters() delivery.DeadLetterStore {
+	return b.delivery.DeadLetters()
+}
+
+// Replay resubmits a dead lettered event to its subscriber, removing it from
+// the dead letter store once delivery succeeds. A failed replay updates the
+// existing entry in place rather than dead lettering it again
+func (b *BridgeImpl) Replay(id string) error {
+	entry, err := b.delivery.DeadLetters().Get(id)
+	if err != nil {
+		return err
+	}
+	_, _, err = b.delivery.Replay(entry)
+	return err
+}
+
+// Drop discards a dead lettered event without attempting redelivery
+func (b *BridgeImpl) Drop(id string) error {
+	if err := b.delivery.DeadLetters().Remove(id); err != nil {
+		return err
 	}
-	return forwarders
+	b.delivery.SyncDLQDepth()
+	return nil
+}
+
+// getListeners returns the subscriptions with a hook of hook_type whose uri
+// regex matches uri, delegating to the store's precompiled patterns
+func (b *BridgeImpl) getListeners(uri, hook_type string) []*client.Subscription {
+	return b.store.Match(uri, hook_type)
+}
+
+// Match exposes getListeners to callers outside the bridge, e.g. the
+// subscriptions api filtering on uri and hook type
+func (b *BridgeImpl) Match(uri, hookType string) []*client.Subscription {
+	return b.getListeners(uri, hookType)
+}
+
+// Stats returns the delivery counters recorded for a subscription
+func (b *BridgeImpl) Stats(subscriptionID string) delivery.Stats {
+	return b.delivery.Stats(subscriptionID)
 }
 
+// generateSubscriptionID produces a cryptographically random, URL-safe
+// subscription id, unguessable to anyone who hasn't seen it returned from Add()
 func (b *BridgeImpl) generateSubscriptionID() string {
-	numbers := []rune("0123456789")
-	id := make([]rune, SUBSCRIPTION_ID_LENGTH)
-	for i := range id {
-		id[i] = numbers[rand.Intn(len(numbers))]
+	raw := make([]byte, SUBSCRIPTION_ID_BYTES)
+	if _, err := rand.Read(raw); err != nil {
+		log.Errorf("Failed to read random bytes for a subscription id, error: %s", err)
 	}
-	return string(id)
+	return base64.RawURLEncoding.EncodeToString(raw)
 }
@@ -0,0 +1,163 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/client"
+	"github.com/gambol99/bridge.io/pkg/bridge/delivery"
+	"github.com/gambol99/bridge.io/pkg/bridge/store"
+)
+
+// newTestBridge starts a Bridge bound to an ephemeral port with a delivery
+// manager tuned for fast, in-process tests
+func newTestBridge(t *testing.T) (Bridge, func()) {
+	dir, err := ioutil.TempDir("", "bridge-test")
+	if err != nil {
+		t.Fatalf("unexpected error creating the temp dir: %s", err)
+	}
+	dlq, err := delivery.NewFileDeadLetterStore(filepath.Join(dir, "dlq.json"))
+	if err != nil {
+		t.Fatalf("unexpected error creating the dead letter store: %s", err)
+	}
+
+	bridge, err := NewBridge(&Config{
+		Name:   "bridge-test",
+		Listen: "127.0.0.1:0",
+		Store:  store.NewMemoryStore(),
+		Delivery: &delivery.Config{
+			MaxRetries: 0,
+			Timeout:    time.Second,
+			BackoffMin: time.Millisecond,
+			BackoffMax: 5 * time.Millisecond,
+			QueueSize:  4,
+			DLQ:        dlq,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating the bridge: %s", err)
+	}
+	return bridge, func() {
+		bridge.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPreHookEventMutatesRequestAndVerifiesSignature(t *testing.T) {
+	bridge, cleanup := newTestBridge(t)
+	defer cleanup()
+
+	secret := "shhh"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutated := []byte(`{"name":"mutated"}`)
+		timestamp := r.Header.Get(client.TimestampHeader)
+		w.Header().Set(client.TimestampHeader, timestamp)
+		w.Header().Set(client.SignatureHeader, client.Sign(secret, timestamp, mutated))
+		_ = body
+		w.Write(mutated)
+	}))
+	defer server.Close()
+
+	if _, err := bridge.Add(&client.Subscription{
+		Endpoint: server.URL,
+		Requests: []*client.HookRequest{{HookType: client.PRE_EVENT, URI: "^/widgets/.*$"}},
+		Format:   client.FORMAT_LEGACY,
+		Secret:   secret,
+	}); err != nil {
+		t.Fatalf("unexpected error adding the subscription: %s", err)
+	}
+
+	mutated, err := bridge.PreHookEvent("/widgets/123", []byte(`{"name":"widget"}`))
+	if err != nil {
+		t.Fatalf("unexpected error from PreHookEvent: %s", err)
+	}
+	if string(mutated) != `{"name":"mutated"}` {
+		t.Fatalf("expected the mutated response from the subscriber, got: %s", mutated)
+	}
+}
+
+func TestPreHookEventRejectsAnUnsignedMutation(t *testing.T) {
+	bridge, cleanup := newTestBridge(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"mutated"}`))
+	}))
+	defer server.Close()
+
+	if _, err := bridge.Add(&client.Subscription{
+		Endpoint: server.URL,
+		Requests: []*client.HookRequest{{HookType: client.PRE_EVENT, URI: "^/widgets/.*$"}},
+		Format:   client.FORMAT_LEGACY,
+		Secret:   "shhh",
+	}); err != nil {
+		t.Fatalf("unexpected error adding the subscription: %s", err)
+	}
+
+	original := []byte(`{"name":"widget"}`)
+	result, err := bridge.PreHookEvent("/widgets/123", original)
+	if err != nil {
+		t.Fatalf("unexpected error from PreHookEvent: %s", err)
+	}
+	if string(result) != string(original) {
+		t.Fatalf("expected the unsigned mutation to be rejected and the original request kept, got: %s", result)
+	}
+}
+
+func TestPostHookEventEnqueuesAsyncDelivery(t *testing.T) {
+	bridge, cleanup := newTestBridge(t)
+	defer cleanup()
+
+	delivered := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		delivered <- body
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if _, err := bridge.Add(&client.Subscription{
+		Endpoint: server.URL,
+		Requests: []*client.HookRequest{{HookType: client.POST_EVENT, URI: "^/widgets/.*$"}},
+		Format:   client.FORMAT_LEGACY,
+	}); err != nil {
+		t.Fatalf("unexpected error adding the subscription: %s", err)
+	}
+
+	body := []byte(`{"name":"widget"}`)
+	result, err := bridge.PostHookEvent("/widgets/123", body)
+	if err != nil {
+		t.Fatalf("unexpected error from PostHookEvent: %s", err)
+	}
+	if string(result) != string(body) {
+		t.Fatalf("expected PostHookEvent to return the request unchanged, got: %s", result)
+	}
+
+	select {
+	case got := <-delivered:
+		if string(got) != string(body) {
+			t.Fatalf("expected the subscriber to receive the request body, got: %s", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the post hook event to be delivered to the subscriber asynchronously")
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of a delivery
+	SignatureHeader = "X-Bridge-Signature"
+	// TimestampHeader carries the unix timestamp the signature was made at
+	TimestampHeader = "X-Bridge-Timestamp"
+	// SubscriptionIDHeader carries the id of the subscription the delivery belongs to
+	SubscriptionIDHeader = "X-Bridge-Subscription-Id"
+)
+
+// Sign computes the signature bridge.io attaches to an outbound webhook (or
+// expects back on a pre hook mutation), binding the timestamp into the mac
+// so a captured payload can't be replayed under a new timestamp without
+// the secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign using a constant time
+// comparison, allowing a subscriber to confirm a request came from the
+// bridge, or the bridge to confirm a mutated response came back from the
+// subscriber it was sent to
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
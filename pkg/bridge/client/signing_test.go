@@ -0,0 +1,61 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import "testing"
+
+func TestSignAndVerifyRoundTrip(t *testing.T) {
+	secret := "shhh"
+	timestamp := "1700000000"
+	body := []byte(`{"hello":"world"}`)
+
+	signature := Sign(secret, timestamp, body)
+	if signature == "" {
+		t.Fatal("expected a non-empty signature")
+	}
+	if !Verify(secret, timestamp, body, signature) {
+		t.Fatal("expected the signature to verify against the same inputs")
+	}
+}
+
+func TestVerifyRejectsTamperedInput(t *testing.T) {
+	secret := "shhh"
+	timestamp := "1700000000"
+	body := []byte(`{"hello":"world"}`)
+	signature := Sign(secret, timestamp, body)
+
+	cases := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+	}{
+		{"wrong secret", "other-secret", timestamp, body},
+		{"wrong timestamp", secret, "1700000001", body},
+		{"wrong body", secret, timestamp, []byte(`{"hello":"mars"}`)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if Verify(c.secret, c.timestamp, c.body, signature) {
+				t.Fatalf("expected verification to fail on %s", c.name)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsGarbageSignature(t *testing.T) {
+	if Verify("shhh", "1700000000", []byte("body"), "not-a-real-signature") {
+		t.Fatal("expected verification to fail for a garbage signature")
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/cloudevents"
+)
+
+const (
+	PRE_EVENT  = "pre"
+	POST_EVENT = "post"
+
+	// FORMAT_LEGACY delivers the raw request body with none of the
+	// bridge's own framing, preserved for subscribers written against
+	// the pre-CloudEvents wire format
+	FORMAT_LEGACY = "legacy"
+)
+
+// a single hook the subscriber wishes to be notified on
+type HookRequest struct {
+	// the type of hook, either PRE_EVENT or POST_EVENT
+	HookType string
+	// the uri regex the hook is interested in
+	URI string
+}
+
+// a subscription is a request from a third party to be notified on one
+// or more hooks
+type Subscription struct {
+	// the unique id given to the subscription on Add()
+	SubscriptionID string
+	// the endpoint to deliver the events to
+	Endpoint string
+	// the hooks the subscriber is interested in
+	Requests []*HookRequest
+	// the time the subscription was registered, set by the bridge on Add()
+	CreatedAt time.Time
+	// the wire format used to deliver events: cloudevents.ModeStructured,
+	// cloudevents.ModeBinary or FORMAT_LEGACY. Defaults to
+	// cloudevents.ModeStructured when empty
+	Format string
+	// the shared secret used to HMAC sign outbound deliveries and verify
+	// mutated responses on pre-hooks, optional
+	Secret string
+}
+
+// DeliveryFormat returns the wire format the subscription should be
+// delivered with, defaulting to a structured mode CloudEvents envelope
+func (r *Subscription) DeliveryFormat() string {
+	if r.Format == "" {
+		return cloudevents.ModeStructured
+	}
+	return r.Format
+}
+
+// Valid checks the subscription request is well formed
+func (r *Subscription) Valid() error {
+	if r.Endpoint == "" {
+		return fmt.Errorf("the subscription has not specified an endpoint")
+	}
+	if len(r.Requests) <= 0 {
+		return fmt.Errorf("the subscription has not specified any hook requests")
+	}
+	for _, hook := range r.Requests {
+		if hook.URI == "" {
+			return fmt.Errorf("the hook request has not specified a uri")
+		}
+		if hook.HookType != PRE_EVENT && hook.HookType != POST_EVENT {
+			return fmt.Errorf("the hook request: %s has an invalid hook type: %s", hook.URI, hook.HookType)
+		}
+	}
+	return nil
+}
+
+func (r Subscription) String() string {
+	return fmt.Sprintf("endpoint: %s, hooks: %d", r.Endpoint, len(r.Requests))
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// ModeStructured delivers a single application/cloudevents+json body
+	ModeStructured = "structured"
+	// ModeBinary delivers the raw request as the body, with the envelope
+	// attributes carried in ce-* headers
+	ModeBinary = "binary"
+)
+
+// StructuredRequest marshals the event as a single application/cloudevents+json
+// body, returning the body and the headers to send it with
+func (e *Event) StructuredRequest() ([]byte, http.Header, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	headers := make(http.Header)
+	headers.Set("Content-Type", ContentType)
+	return body, headers, nil
+}
+
+// BinaryRequest returns the raw request payload as the body, with the
+// CloudEvents attributes carried as ce-* headers per the HTTP binary binding
+func (e *Event) BinaryRequest() ([]byte, http.Header, error) {
+	headers := make(http.Header)
+	headers.Set("ce-specversion", e.SpecVersion)
+	headers.Set("ce-id", e.ID)
+	headers.Set("ce-source", e.Source)
+	headers.Set("ce-type", e.Type)
+	headers.Set("ce-subject", e.Subject)
+	headers.Set("ce-time", e.Time.Format(time.RFC3339Nano))
+	headers.Set("Content-Type", e.DataContentType)
+	return e.Payload(), headers, nil
+}
+
+// ParseResponse unwraps a CloudEvents shaped response returned by a
+// subscriber on a pre hook delivery, returning the mutated request body.
+// A response carrying the "ce-specversion" header is treated as binary
+// mode, where the body already is the raw data; otherwise the body is
+// parsed as a structured mode envelope
+func ParseResponse(headers http.Header, body []byte) ([]byte, error) {
+	if headers != nil && headers.Get("ce-specversion") != "" {
+		return body, nil
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse the cloudevents response, error: %s", err)
+	}
+	return event.Payload(), nil
+}
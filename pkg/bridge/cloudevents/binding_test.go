@@ -0,0 +1,70 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStructuredRequestRoundTripsThroughParseResponse(t *testing.T) {
+	body := []byte(`{"name":"widget"}`)
+	event := NewPreHookEvent("host/bridge.io", "/widgets/123", body)
+
+	payload, headers, err := event.StructuredRequest()
+	if err != nil {
+		t.Fatalf("unexpected error building the structured request: %s", err)
+	}
+	if headers.Get("Content-Type") != ContentType {
+		t.Fatalf("expected content type: %s, got: %s", ContentType, headers.Get("Content-Type"))
+	}
+
+	mutated, err := ParseResponse(nil, payload)
+	if err != nil {
+		t.Fatalf("unexpected error parsing the structured response: %s", err)
+	}
+	if !bytes.Equal(mutated, body) {
+		t.Fatalf("expected the payload to round trip, got: %s", mutated)
+	}
+}
+
+func TestBinaryRequestRoundTripsThroughParseResponse(t *testing.T) {
+	body := []byte(`{"name":"widget"}`)
+	event := NewPreHookEvent("host/bridge.io", "/widgets/123", body)
+
+	payload, headers, err := event.BinaryRequest()
+	if err != nil {
+		t.Fatalf("unexpected error building the binary request: %s", err)
+	}
+	if headers.Get("ce-specversion") != SpecVersion {
+		t.Fatalf("expected ce-specversion: %s, got: %s", SpecVersion, headers.Get("ce-specversion"))
+	}
+	if headers.Get("ce-id") != event.ID {
+		t.Fatalf("expected ce-id: %s, got: %s", event.ID, headers.Get("ce-id"))
+	}
+
+	mutated, err := ParseResponse(headers, payload)
+	if err != nil {
+		t.Fatalf("unexpected error parsing the binary response: %s", err)
+	}
+	if !bytes.Equal(mutated, body) {
+		t.Fatalf("expected the payload to round trip, got: %s", mutated)
+	}
+}
+
+func TestParseResponseRejectsMalformedStructuredBody(t *testing.T) {
+	if _, err := ParseResponse(nil, []byte("not an envelope")); err == nil {
+		t.Fatal("expected an error parsing a malformed structured response")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudevents wraps bridge hook events in a CloudEvents 1.0
+// envelope so subscribers can be delivered to over the standard
+// structured or binary HTTP bindings, rather than the bridge's own
+// ad-hoc request format
+package cloudevents
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// SpecVersion is the CloudEvents specification version implemented
+	SpecVersion = "1.0"
+
+	// TypePreHook is the event type used for pre hook deliveries
+	TypePreHook = "io.bridge.prehook"
+	// TypePostHook is the event type used for post hook deliveries
+	TypePostHook = "io.bridge.posthook"
+
+	// ContentType is the media type of a structured mode envelope
+	ContentType = "application/cloudevents+json"
+)
+
+// Event is a CloudEvents 1.0 envelope around a bridge hook request
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+}
+
+// Source builds the CloudEvents "source" attribute from the local
+// hostname and the bridge's name, e.g. "my-host/bridge.io"
+func Source(name string) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s/%s", host, name)
+}
+
+// NewPreHookEvent creates the envelope sent to subscribers on a pre hook event
+func NewPreHookEvent(source, uri string, body []byte) *Event {
+	return newEvent(source, TypePreHook, uri, body)
+}
+
+// NewPostHookEvent creates the envelope sent to subscribers on a post hook event
+func NewPostHookEvent(source, uri string, body []byte) *Event {
+	return newEvent(source, TypePostHook, uri, body)
+}
+
+func newEvent(source, eventType, uri string, body []byte) *Event {
+	event := &Event{
+		SpecVersion:     SpecVersion,
+		ID:              newEventID(),
+		Source:          source,
+		Type:            eventType,
+		Subject:         uri,
+		Time:            time.Now(),
+		DataContentType: "application/json",
+	}
+	if json.Valid(body) {
+		event.Data = json.RawMessage(body)
+	} else {
+		event.DataBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+	return event
+}
+
+// Payload returns the raw request body carried by the event, decoding it
+// from data_base64 if the original body was not valid json
+func (e *Event) Payload() []byte {
+	if e.DataBase64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(e.DataBase64)
+		if err != nil {
+			return nil
+		}
+		return decoded
+	}
+	return []byte(e.Data)
+}
+
+func newEventID() string {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewPreHookEventCarriesJSONData(t *testing.T) {
+	body := []byte(`{"name":"widget"}`)
+	event := NewPreHookEvent("host/bridge.io", "/widgets/123", body)
+
+	if event.Type != TypePreHook {
+		t.Fatalf("expected type: %s, got: %s", TypePreHook, event.Type)
+	}
+	if event.SpecVersion != SpecVersion {
+		t.Fatalf("expected spec version: %s, got: %s", SpecVersion, event.SpecVersion)
+	}
+	if event.DataBase64 != "" {
+		t.Fatal("expected valid json to be carried as data, not data_base64")
+	}
+	if !bytes.Equal(event.Payload(), body) {
+		t.Fatalf("expected the payload to round trip, got: %s", event.Payload())
+	}
+}
+
+func TestNewPostHookEventEncodesNonJSONAsBase64(t *testing.T) {
+	body := []byte("not json at all")
+	event := NewPostHookEvent("host/bridge.io", "/widgets/123", body)
+
+	if event.Type != TypePostHook {
+		t.Fatalf("expected type: %s, got: %s", TypePostHook, event.Type)
+	}
+	if event.DataBase64 == "" {
+		t.Fatal("expected non-json data to be carried as data_base64")
+	}
+	if !bytes.Equal(event.Payload(), body) {
+		t.Fatalf("expected the payload to round trip, got: %s", event.Payload())
+	}
+}
+
+func TestNewEventIDsAreUnique(t *testing.T) {
+	first := NewPreHookEvent("host/bridge.io", "/widgets/123", []byte(`{}`))
+	second := NewPreHookEvent("host/bridge.io", "/widgets/123", []byte(`{}`))
+	if first.ID == second.ID {
+		t.Fatal("expected two events to be given distinct ids")
+	}
+}
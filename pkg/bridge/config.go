@@ -0,0 +1,48 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"time"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/delivery"
+	"github.com/gambol99/bridge.io/pkg/bridge/store"
+)
+
+// the configuration for the bridge
+type Config struct {
+	// the name of this bridge instance, used as part of the CloudEvents
+	// "source" attribute on outbound deliveries
+	Name string
+	// the interface the bridge api should listen on, i.e. 127.0.0.1:8080
+	Listen string
+	// the timeout applied to calls made to subscribers, propagated onto
+	// Delivery.Timeout by NewBridge if set
+	RequestTimeout time.Duration
+	// the delivery configuration used for retries, backoff and the dead letter queue
+	Delivery *delivery.Config
+	// the store subscriptions are persisted to, defaults to an in-memory
+	// store if left nil
+	Store store.Store
+}
+
+// DefaultConfig returns a sane, default configuration for the bridge
+func DefaultConfig() *Config {
+	return &Config{
+		Name:           "bridge.io",
+		Listen:         "127.0.0.1:8080",
+		RequestTimeout: 10 * time.Second,
+		Delivery:       delivery.DefaultConfig(),
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import "time"
+
+// Config controls the retry, backoff and queueing behaviour of the
+// delivery subsystem
+type Config struct {
+	// the maximum number of retries made after the first delivery attempt,
+	// i.e. up to MaxRetries+1 attempts in all; a value of zero disables
+	// retrying entirely, i.e. a single attempt is made
+	MaxRetries int
+	// the timeout applied to each individual delivery attempt
+	Timeout time.Duration
+	// the initial backoff duration between attempts
+	BackoffMin time.Duration
+	// the ceiling the backoff duration will not exceed
+	BackoffMax time.Duration
+	// the number of events a single subscription's queue will buffer before
+	// further PostHookEvent deliveries are sent straight to the dead letter store
+	QueueSize int
+	// the dead letter store used to persist events which have exhausted
+	// their retries
+	DLQ DeadLetterStore
+}
+
+// DefaultConfig returns the delivery configuration used when none has
+// been provided
+func DefaultConfig() *Config {
+	return &Config{
+		MaxRetries: 5,
+		Timeout:    10 * time.Second,
+		BackoffMin: 500 * time.Millisecond,
+		BackoffMax: 30 * time.Second,
+		QueueSize:  256,
+	}
+}
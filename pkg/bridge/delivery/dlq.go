@@ -0,0 +1,56 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import "time"
+
+// DeadLetterEntry is an event which has exhausted its delivery attempts
+// and been parked for operator attention
+type DeadLetterEntry struct {
+	// a unique id for the entry
+	ID string
+	// the subscription the event was destined for
+	SubscriptionID string
+	// the endpoint the event could not be delivered to
+	Endpoint string
+	// the hook type, client.PRE_EVENT or client.POST_EVENT
+	HookType string
+	// the uri of the resource the event concerns
+	URI string
+	// the body which was, or would have been, delivered
+	Body []byte
+	// the number of attempts made before the event was parked
+	Attempts int
+	// the error returned on the last attempt
+	LastError string
+	// the time the event was parked
+	Stamp time.Time
+}
+
+// DeadLetterStore is implemented by anything capable of persisting
+// undelivered events for later inspection or replay. Implementations are
+// expected to be safe for concurrent use
+type DeadLetterStore interface {
+	// Put persists a dead lettered event
+	Put(entry *DeadLetterEntry) error
+	// List returns the current contents of the dead letter queue
+	List() ([]*DeadLetterEntry, error)
+	// Get returns a single entry by id
+	Get(id string) (*DeadLetterEntry, error)
+	// Remove drops an entry, e.g. once it has been replayed or is no
+	// longer wanted
+	Remove(id string) error
+	// Close releases any resources held by the store
+	Close() error
+}
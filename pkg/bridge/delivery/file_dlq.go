@@ -0,0 +1,144 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileDeadLetterStore is a DeadLetterStore backed by a single json file on
+// disk. It favours simplicity over throughput and is intended as the
+// default store for single node deployments; BoltDB or SQL backed stores
+// can be swapped in via the Config.DLQ field for anything heavier
+type FileDeadLetterStore struct {
+	sync.Mutex
+	// the path of the file the entries are persisted to
+	path string
+}
+
+// NewFileDeadLetterStore creates a DeadLetterStore which persists entries
+// to the given path
+//	path:		the file the entries should be persisted to
+func NewFileDeadLetterStore(path string) (*FileDeadLetterStore, error) {
+	store := &FileDeadLetterStore{path: path}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := store.save(make(map[string]*DeadLetterEntry)); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func (s *FileDeadLetterStore) Put(entry *DeadLetterEntry) error {
+	s.Lock()
+	defer s.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	entries[entry.ID] = entry
+	return s.save(entries)
+}
+
+func (s *FileDeadLetterStore) List() ([]*DeadLetterEntry, error) {
+	s.Lock()
+	defer s.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*DeadLetterEntry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	return list, nil
+}
+
+func (s *FileDeadLetterStore) Get(id string) (*DeadLetterEntry, error) {
+	s.Lock()
+	defer s.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	entry, found := entries[id]
+	if !found {
+		return nil, fmt.Errorf("the dead letter entry: %s does not exist", id)
+	}
+	return entry, nil
+}
+
+func (s *FileDeadLetterStore) Remove(id string) error {
+	s.Lock()
+	defer s.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, found := entries[id]; !found {
+		return fmt.Errorf("the dead letter entry: %s does not exist", id)
+	}
+	delete(entries, id)
+	return s.save(entries)
+}
+
+func (s *FileDeadLetterStore) Close() error {
+	return nil
+}
+
+func (s *FileDeadLetterStore) load() (map[string]*DeadLetterEntry, error) {
+	content, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*DeadLetterEntry)
+	if len(content) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// save flushes the full set of entries to s.path. The write goes to a temp
+// file in the same directory followed by a rename, so a crash or kill
+// mid-write leaves the previous, still valid file in place rather than a
+// truncated one
+func (s *FileDeadLetterStore) save(entries map[string]*DeadLetterEntry) error {
+	content, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileDeadLetterStorePutGetListRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridge-dlq-test")
+	if err != nil {
+		t.Fatalf("unexpected error creating the temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dlq.json")
+
+	store, err := NewFileDeadLetterStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating the dead letter store: %s", err)
+	}
+
+	entry := &DeadLetterEntry{
+		ID:             "entry-1",
+		SubscriptionID: "sub-1",
+		Endpoint:       "http://example.com/hook",
+		HookType:       "post",
+		URI:            "/widgets/123",
+		Body:           []byte(`{"name":"widget"}`),
+		Attempts:       6,
+		LastError:      "subscriber responded with status: 500",
+	}
+	if err := store.Put(entry); err != nil {
+		t.Fatalf("unexpected error putting the entry: %s", err)
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("unexpected error listing entries: %s", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected a single entry, got: %d", len(list))
+	}
+
+	found, err := store.Get("entry-1")
+	if err != nil {
+		t.Fatalf("unexpected error getting the entry: %s", err)
+	}
+	if found.LastError != entry.LastError {
+		t.Fatalf("expected the entry to round trip, got: %+v", found)
+	}
+
+	if err := store.Remove("entry-1"); err != nil {
+		t.Fatalf("unexpected error removing the entry: %s", err)
+	}
+	if _, err := store.Get("entry-1"); err == nil {
+		t.Fatal("expected an error getting a removed entry")
+	}
+}
+
+// TestFileDeadLetterStorePersistLeavesNoTempFiles guards the atomic write:
+// save() must not leave its temp file behind once the rename into place
+// succeeds
+func TestFileDeadLetterStorePersistLeavesNoTempFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridge-dlq-test")
+	if err != nil {
+		t.Fatalf("unexpected error creating the temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dlq.json")
+
+	store, err := NewFileDeadLetterStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating the dead letter store: %s", err)
+	}
+	if err := store.Put(&DeadLetterEntry{ID: "entry-1", SubscriptionID: "sub-1"}); err != nil {
+		t.Fatalf("unexpected error putting the entry: %s", err)
+	}
+
+	leftover, err := filepath.Glob(filepath.Join(dir, "*.tmp*"))
+	if err != nil {
+		t.Fatalf("unexpected error globbing the store dir: %s", err)
+	}
+	if len(leftover) != 0 {
+		t.Fatalf("expected no leftover temp files after save, found: %v", leftover)
+	}
+}
+
+func TestFileDeadLetterStoreRemoveUnknown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridge-dlq-test")
+	if err != nil {
+		t.Fatalf("unexpected error creating the temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dlq.json")
+
+	store, err := NewFileDeadLetterStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating the dead letter store: %s", err)
+	}
+	if err := store.Remove("does-not-exist"); err == nil {
+		t.Fatal("expected an error removing an unknown entry")
+	}
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/metrics"
+
+	log "github.com/Sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records a span around every subscriber delivery attempt
+var tracer = otel.Tracer("github.com/gambol99/bridge.io/pkg/bridge/delivery")
+
+// Job is a single event destined for a subscriber
+type Job struct {
+	// the id of the subscription the event is being delivered to
+	SubscriptionID string
+	// the endpoint to deliver the event to
+	Endpoint string
+	// the hook type, client.PRE_EVENT or client.POST_EVENT
+	HookType string
+	// the uri of the resource the event concerns
+	URI string
+	// the body to deliver
+	Body []byte
+	// additional headers to send with the request, e.g. the CloudEvents
+	// ce-* binary mode headers
+	Headers http.Header
+}
+
+// Stats is a snapshot of the delivery counters for a subscription
+type Stats struct {
+	Delivered int64
+	Failed    int64
+	LastError string
+}
+
+// subscriberQueue is the buffered job channel and worker lifetime for a
+// single subscription
+type subscriberQueue struct {
+	jobs chan *Job
+	stop chan struct{}
+}
+
+// Manager fans events out to per-subscription worker queues, retrying
+// each delivery with an exponential backoff before parking it in the dead
+// letter store
+type Manager struct {
+	sync.Mutex
+	// the delivery configuration
+	config *Config
+	// the http client used to call the subscribers
+	client *http.Client
+	// a bounded worker queue per subscription
+	queues map[string]*subscriberQueue
+	// delivery counters, keyed by subscription id
+	stats map[string]*Stats
+	// tracks the lifetime of every worker goroutine, so Close can wait for
+	// them to drain
+	wg sync.WaitGroup
+}
+
+// NewManager creates a delivery Manager from the given configuration. If
+// cfg.DLQ is nil a FileDeadLetterStore rooted at "bridge-dlq.json" is used
+func NewManager(cfg *Config) (*Manager, error) {
+	if cfg.DLQ == nil {
+		store, err := NewFileDeadLetterStore("bridge-dlq.json")
+		if err != nil {
+			return nil, err
+		}
+		cfg.DLQ = store
+	}
+	return &Manager{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		queues: make(map[string]*subscriberQueue),
+		stats:  make(map[string]*Stats),
+	}, nil
+}
+
+// DeadLetters exposes the underlying dead letter store, used by the
+// BridgeAPI admin endpoints to list, replay and drop entries
+func (m *Manager) DeadLetters() DeadLetterStore {
+	return m.config.DLQ
+}
+
+// SyncDLQDepth recomputes and publishes the dead letter queue depth metric,
+// called by the bridge after it removes an entry from the store directly,
+// i.e. on an admin drop; Replay keeps the metric in sync itself
+func (m *Manager) SyncDLQDepth() {
+	m.syncDLQDepth()
+}
+
+// Stats returns a snapshot of the delivery counters for a subscription
+func (m *Manager) Stats(subscriptionID string) Stats {
+	m.Lock()
+	defer m.Unlock()
+	if stats, found := m.stats[subscriptionID]; found {
+		return *stats
+	}
+	return Stats{}
+}
+
+// Send delivers a job synchronously, retrying with backoff, and returns
+// the subscriber's response body and headers. Used by pre-hooks, where the
+// mutated request must be available before the caller can continue
+func (m *Manager) Send(job *Job) ([]byte, http.Header, error) {
+	return m.deliver(job)
+}
+
+// Enqueue places a job onto its subscription's worker queue for fully
+// asynchronous delivery, used by post-hooks. If the queue is full the
+// event is dropped straight to the dead letter store rather than
+// blocking the caller
+func (m *Manager) Enqueue(job *Job) {
+	queue := m.queueFor(job.SubscriptionID)
+	select {
+	case queue.jobs <- job:
+	default:
+		log.Errorf("The delivery queue for subscription: %s is full, dead lettering event", job.SubscriptionID)
+		m.deadLetter(job, 0, fmt.Errorf("delivery queue is full"))
+	}
+}
+
+func (m *Manager) queueFor(subscriptionID string) *subscriberQueue {
+	m.Lock()
+	defer m.Unlock()
+	queue, found := m.queues[subscriptionID]
+	if !found {
+		queue = &subscriberQueue{
+			jobs: make(chan *Job, m.config.QueueSize),
+			stop: make(chan struct{}),
+		}
+		m.queues[subscriptionID] = queue
+		m.wg.Add(1)
+		go m.worker(queue)
+	}
+	return queue
+}
+
+func (m *Manager) worker(queue *subscriberQueue) {
+	defer m.wg.Done()
+	for {
+		select {
+		case job := <-queue.jobs:
+			if _, _, err := m.deliver(job); err != nil {
+				log.Errorf("Failed to deliver event to subscriber: %s, error: %s", job.Endpoint, err)
+			}
+		case <-queue.stop:
+			return
+		}
+	}
+}
+
+// RemoveQueue stops and discards the worker queue for a subscription,
+// called once the subscription itself has been removed from the bridge.
+// The worker exits at its next select once stop is closed, so jobs still
+// buffered on the queue may be dropped rather than delivered
+func (m *Manager) RemoveQueue(subscriptionID string) {
+	m.Lock()
+	queue, found := m.queues[subscriptionID]
+	if found {
+		delete(m.queues, subscriptionID)
+	}
+	m.Unlock()
+	if found {
+		close(queue.stop)
+	}
+}
+
+// Close stops every subscriber worker and releases the dead letter store,
+// called when the bridge itself is shut down
+func (m *Manager) Close() error {
+	m.Lock()
+	queues := m.queues
+	m.queues = make(map[string]*subscriberQueue)
+	m.Unlock()
+
+	for _, queue := range queues {
+		close(queue.stop)
+	}
+	m.wg.Wait()
+
+	return m.config.DLQ.Close()
+}
+
+// deliver performs the http call, retrying with an exponential backoff
+// and jitter up to config.MaxRetries times before dead lettering the
+// event as a brand new entry
+func (m *Manager) deliver(job *Job) ([]byte, http.Header, error) {
+	body, headers, attempts, err := m.attemptWithRetries(job)
+	if err != nil {
+		m.recordFailure(job.SubscriptionID, err)
+		m.deadLetter(job, attempts, err)
+		return nil, nil, err
+	}
+	m.recordSuccess(job.SubscriptionID)
+	return body, headers, nil
+}
+
+// Replay resubmits a dead lettered entry to its subscriber. On success the
+// entry is removed from the dead letter store; on failure the existing
+// entry is updated in place rather than dead lettered again, so a failed
+// replay does not leave a duplicate entry behind
+func (m *Manager) Replay(entry *DeadLetterEntry) ([]byte, http.Header, error) {
+	job := &Job{
+		SubscriptionID: entry.SubscriptionID,
+		Endpoint:       entry.Endpoint,
+		HookType:       entry.HookType,
+		URI:            entry.URI,
+		Body:           entry.Body,
+	}
+	body, headers, attempts, err := m.attemptWithRetries(job)
+	if err != nil {
+		m.recordFailure(job.SubscriptionID, err)
+		entry.Attempts += attempts
+		entry.LastError = err.Error()
+		entry.Stamp = time.Now()
+		if putErr := m.config.DLQ.Put(entry); putErr != nil {
+			log.Errorf("Failed to update the dead letter entry: %s after a failed replay, error: %s", entry.ID, putErr)
+		}
+		m.syncDLQDepth()
+		return nil, nil, err
+	}
+	m.recordSuccess(job.SubscriptionID)
+	if err := m.config.DLQ.Remove(entry.ID); err != nil {
+		log.Errorf("Failed to remove the dead letter entry: %s after a successful replay, error: %s", entry.ID, err)
+	}
+	m.syncDLQDepth()
+	return body, headers, nil
+}
+
+// attemptWithRetries calls attempt up to config.MaxRetries+1 times with an
+// exponential backoff between failures, returning the number of attempts
+// made alongside the usual result
+func (m *Manager) attemptWithRetries(job *Job) ([]byte, http.Header, int, error) {
+	attempts := m.config.MaxRetries + 1
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		body, headers, err := m.attempt(job)
+		if err == nil {
+			metrics.ObserveDeliveryAttempt(job.SubscriptionID, "success")
+			return body, headers, attempt, nil
+		}
+		lastErr = err
+		metrics.ObserveDeliveryAttempt(job.SubscriptionID, "failure")
+		log.Errorf("Attempt %d/%d to deliver event to subscriber: %s failed, error: %s", attempt, attempts, job.Endpoint, err)
+		if attempt < attempts {
+			time.Sleep(m.backoff(attempt))
+		}
+	}
+	return nil, nil, attempts, lastErr
+}
+
+// attempt makes a single delivery call to the subscriber, wrapped in a span
+// so the hop can be correlated with the rest of the request's trace, and
+// with the span's W3C traceparent injected into the outbound headers so the
+// subscriber can continue the trace
+func (m *Manager) attempt(job *Job) ([]byte, http.Header, error) {
+	ctx, span := tracer.Start(context.Background(), "delivery.attempt",
+		trace.WithAttributes(
+			attribute.String("bridge.endpoint", job.Endpoint),
+			attribute.String("bridge.uri", job.URI),
+			attribute.String("bridge.hook_type", job.HookType),
+			attribute.String("bridge.subscription_id", job.SubscriptionID),
+		),
+	)
+	defer span.End()
+
+	request, err := http.NewRequestWithContext(ctx, "POST", job.Endpoint, bytes.NewReader(job.Body))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, nil, err
+	}
+	for name, values := range job.Headers {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	response, err := m.client.Do(request)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", response.StatusCode))
+	if response.StatusCode >= 300 {
+		err := fmt.Errorf("subscriber responded with status: %d", response.StatusCode)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, nil, err
+	}
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, nil, err
+	}
+	return content, response.Header, nil
+}
+
+// backoff returns an exponential backoff duration, bounded by
+// config.BackoffMax and jittered to avoid thundering herds against the
+// subscriber
+func (m *Manager) backoff(attempt int) time.Duration {
+	backoff := m.config.BackoffMin * time.Duration(1<<uint(attempt-1))
+	if backoff > m.config.BackoffMax || backoff <= 0 {
+		backoff = m.config.BackoffMax
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff) / 2))
+	return backoff/2 + jitter
+}
+
+func (m *Manager) deadLetter(job *Job, attempts int, cause error) {
+	entry := &DeadLetterEntry{
+		ID:             fmt.Sprintf("%s-%d", job.SubscriptionID, time.Now().UnixNano()),
+		SubscriptionID: job.SubscriptionID,
+		Endpoint:       job.Endpoint,
+		HookType:       job.HookType,
+		URI:            job.URI,
+		Body:           job.Body,
+		Attempts:       attempts,
+		LastError:      cause.Error(),
+		Stamp:          time.Now(),
+	}
+	if err := m.config.DLQ.Put(entry); err != nil {
+		log.Errorf("Failed to persist dead letter entry for subscription: %s, error: %s", job.SubscriptionID, err)
+	}
+	m.syncDLQDepth()
+}
+
+// syncDLQDepth recomputes the dead letter queue depth and publishes it to
+// the DLQDepth gauge, called whenever an entry is added or removed
+func (m *Manager) syncDLQDepth() {
+	entries, err := m.config.DLQ.List()
+	if err != nil {
+		log.Errorf("Failed to list the dead letter queue to update its depth metric, error: %s", err)
+		return
+	}
+	metrics.SetDLQDepth(len(entries))
+}
+
+func (m *Manager) recordSuccess(subscriptionID string) {
+	m.Lock()
+	defer m.Unlock()
+	m.statsFor(subscriptionID)
+	m.stats[subscriptionID].Delivered++
+}
+
+func (m *Manager) recordFailure(subscriptionID string, cause error) {
+	m.Lock()
+	defer m.Unlock()
+	m.statsFor(subscriptionID)
+	m.stats[subscriptionID].Failed++
+	m.stats[subscriptionID].LastError = cause.Error()
+}
+
+// statsFor must be called with the lock held
+func (m *Manager) statsFor(subscriptionID string) {
+	if _, found := m.stats[subscriptionID]; !found {
+		m.stats[subscriptionID] = &Stats{}
+	}
+}
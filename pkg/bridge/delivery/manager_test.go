@@ -0,0 +1,219 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T, maxRetries int) (*Manager, func()) {
+	dir, err := ioutil.TempDir("", "bridge-manager-test")
+	if err != nil {
+		t.Fatalf("unexpected error creating the temp dir: %s", err)
+	}
+	dlq, err := NewFileDeadLetterStore(filepath.Join(dir, "dlq.json"))
+	if err != nil {
+		t.Fatalf("unexpected error creating the dead letter store: %s", err)
+	}
+	manager, err := NewManager(&Config{
+		MaxRetries: maxRetries,
+		Timeout:    time.Second,
+		BackoffMin: time.Millisecond,
+		BackoffMax: 5 * time.Millisecond,
+		QueueSize:  4,
+		DLQ:        dlq,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating the manager: %s", err)
+	}
+	return manager, func() { os.RemoveAll(dir) }
+}
+
+func TestBackoffIsBoundedByBackoffMax(t *testing.T) {
+	manager, cleanup := newTestManager(t, 5)
+	defer cleanup()
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		backoff := manager.backoff(attempt)
+		if backoff <= 0 {
+			t.Fatalf("expected a positive backoff on attempt %d, got: %s", attempt, backoff)
+		}
+		if backoff > manager.config.BackoffMax {
+			t.Fatalf("expected backoff to be bounded by BackoffMax: %s, got: %s on attempt %d", manager.config.BackoffMax, backoff, attempt)
+		}
+	}
+}
+
+func TestSendSucceedsWithoutRetrying(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	manager, cleanup := newTestManager(t, 5)
+	defer cleanup()
+
+	body, _, err := manager.Send(&Job{SubscriptionID: "sub-1", Endpoint: server.URL, Body: []byte("hello")})
+	if err != nil {
+		t.Fatalf("unexpected error sending the job: %s", err)
+	}
+	if string(body) != "ok" {
+		t.Fatalf("expected the subscriber's response body, got: %s", body)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected exactly one delivery attempt, got: %d", got)
+	}
+
+	if stats := manager.Stats("sub-1"); stats.Delivered != 1 {
+		t.Fatalf("expected one delivered stat, got: %+v", stats)
+	}
+}
+
+func TestSendRetriesThenDeadLettersOnExhaustion(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	manager, cleanup := newTestManager(t, 2)
+	defer cleanup()
+
+	if _, _, err := manager.Send(&Job{SubscriptionID: "sub-1", Endpoint: server.URL, Body: []byte("hello")}); err == nil {
+		t.Fatal("expected an error once retries are exhausted")
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("expected MaxRetries+1 = 3 attempts, got: %d", got)
+	}
+
+	entries, err := manager.DeadLetters().List()
+	if err != nil {
+		t.Fatalf("unexpected error listing dead letters: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly one dead lettered entry, got: %d", len(entries))
+	}
+	if entries[0].Attempts != 3 {
+		t.Fatalf("expected the entry to record 3 attempts, got: %d", entries[0].Attempts)
+	}
+
+	if stats := manager.Stats("sub-1"); stats.Failed != 1 {
+		t.Fatalf("expected one failed stat, got: %+v", stats)
+	}
+}
+
+func TestReplaySuccessRemovesTheEntry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	manager, cleanup := newTestManager(t, 0)
+	defer cleanup()
+
+	if _, _, err := manager.Send(&Job{SubscriptionID: "sub-1", Endpoint: server.URL, Body: []byte("hello")}); err == nil {
+		t.Fatal("expected the initial send to fail and dead letter the event")
+	}
+	entries, err := manager.DeadLetters().List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected exactly one dead lettered entry, got: %d, error: %v", len(entries), err)
+	}
+
+	if _, _, err := manager.Replay(entries[0]); err != nil {
+		t.Fatalf("unexpected error replaying the entry: %s", err)
+	}
+
+	remaining, err := manager.DeadLetters().List()
+	if err != nil {
+		t.Fatalf("unexpected error listing dead letters: %s", err)
+	}
+	if len(remaining) != 0 {
+		t.Fatalf("expected the dead letter entry to be removed on a successful replay, got: %d remaining", len(remaining))
+	}
+}
+
+// TestReplayFailureUpdatesTheEntryInPlace guards against a failed replay
+// dead lettering the event a second time under a new id
+func TestReplayFailureUpdatesTheEntryInPlace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	manager, cleanup := newTestManager(t, 0)
+	defer cleanup()
+
+	if _, _, err := manager.Send(&Job{SubscriptionID: "sub-1", Endpoint: server.URL, Body: []byte("hello")}); err == nil {
+		t.Fatal("expected the initial send to fail and dead letter the event")
+	}
+	entries, err := manager.DeadLetters().List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected exactly one dead lettered entry, got: %d, error: %v", len(entries), err)
+	}
+	originalID := entries[0].ID
+	originalAttempts := entries[0].Attempts
+
+	if _, _, err := manager.Replay(entries[0]); err == nil {
+		t.Fatal("expected the replay to fail, the subscriber always returns 500")
+	}
+
+	remaining, err := manager.DeadLetters().List()
+	if err != nil {
+		t.Fatalf("unexpected error listing dead letters: %s", err)
+	}
+	if len(remaining) != 1 {
+		t.Fatalf("expected a failed replay to update the entry in place, not duplicate it, found: %d entries", len(remaining))
+	}
+	if remaining[0].ID != originalID {
+		t.Fatalf("expected the entry id to be unchanged, got: %s, want: %s", remaining[0].ID, originalID)
+	}
+	if remaining[0].Attempts <= originalAttempts {
+		t.Fatalf("expected the attempts count to grow after a failed replay, before: %d, after: %d", originalAttempts, remaining[0].Attempts)
+	}
+}
+
+func TestRemoveQueueAndCloseStopWorkers(t *testing.T) {
+	manager, cleanup := newTestManager(t, 0)
+	defer cleanup()
+
+	manager.Enqueue(&Job{SubscriptionID: "sub-1", Endpoint: "http://127.0.0.1:0", Body: []byte("hello")})
+	manager.RemoveQueue("sub-1")
+
+	done := make(chan struct{})
+	go func() {
+		manager.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Close to return once every worker has stopped")
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"github.com/gambol99/bridge.io/pkg/bridge/client"
+	"github.com/gambol99/bridge.io/pkg/bridge/delivery"
+)
+
+// Bridge is the core interface used by the api and the sinks to interact
+// with the subscription and hook delivery system
+type Bridge interface {
+	// Add registers a new subscription with the bridge, returning the
+	// subscription id on success
+	Add(subscription *client.Subscription) (string, error)
+	// Remove deletes the subscription with the given id
+	Remove(id string) error
+	// PreHookEvent is called before the request is forwarded to the sink
+	PreHookEvent(uri string, request []byte) ([]byte, error)
+	// PostHookEvent is called once the sink has responded
+	PostHookEvent(uri string, request []byte) ([]byte, error)
+	// Subscriptions returns the current subscriptions held by the bridge
+	Subscriptions() []*client.Subscription
+	// Get returns a single subscription by id
+	Get(id string) (*client.Subscription, error)
+	// Match returns the subscriptions with a hook of hookType whose uri
+	// regex matches uri
+	Match(uri, hookType string) []*client.Subscription
+	// Stats returns the delivery counters recorded for a subscription
+	Stats(subscriptionID string) delivery.Stats
+	// DeadLetters exposes the dead letter store, used by the admin api to
+	// list and drop undelivered events
+	DeadLetters() delivery.DeadLetterStore
+	// Replay resubmits a dead lettered event to its subscriber, removing it
+	// from the dead letter store on success
+	Replay(id string) error
+	// Drop discards a dead lettered event without attempting redelivery
+	Drop(id string) error
+	// Close releases any resources being used by the bridge
+	Close() error
+}
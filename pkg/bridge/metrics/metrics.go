@@ -0,0 +1,83 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the Prometheus collectors exported by the bridge,
+// and the small helpers used to update them from the hook handlers and the
+// delivery manager
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// HookEventsTotal counts hook events, labelled by outcome. The uri is
+	// deliberately not a label here: it is caller-controlled and unbounded,
+	// and would otherwise grow the metric's cardinality without limit
+	HookEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bridge_hook_events_total",
+		Help: "Total number of pre/post hook events received by the bridge",
+	}, []string{"hook_type", "result"})
+
+	// HookDuration tracks how long a hook event took to fan out to its subscribers
+	HookDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bridge_hook_duration_seconds",
+		Help:    "Time taken to fan a hook event out to its subscribers",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"hook_type"})
+
+	// SubscribersTotal is the current number of registered subscriptions
+	SubscribersTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "bridge_subscribers_total",
+		Help: "Current number of subscriptions registered with the bridge",
+	})
+
+	// DeliveryAttemptsTotal counts individual delivery attempts made to a subscriber
+	DeliveryAttemptsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bridge_subscription_delivery_attempts_total",
+		Help: "Total number of delivery attempts made to a subscriber",
+	}, []string{"subscription_id", "result"})
+
+	// DLQDepth is the current number of entries parked in the dead letter queue
+	DLQDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "bridge_dlq_depth",
+		Help: "Current number of events parked in the dead letter queue",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(HookEventsTotal, HookDuration, SubscribersTotal, DeliveryAttemptsTotal, DLQDepth)
+}
+
+// ObserveHookEvent records the outcome and duration of a completed hook event
+func ObserveHookEvent(hookType, result string, duration time.Duration) {
+	HookEventsTotal.WithLabelValues(hookType, result).Inc()
+	HookDuration.WithLabelValues(hookType).Observe(duration.Seconds())
+}
+
+// SetSubscribersTotal records the current number of registered subscriptions
+func SetSubscribersTotal(count int) {
+	SubscribersTotal.Set(float64(count))
+}
+
+// ObserveDeliveryAttempt records the outcome of a single delivery attempt
+func ObserveDeliveryAttempt(subscriptionID, result string) {
+	DeliveryAttemptsTotal.WithLabelValues(subscriptionID, result).Inc()
+}
+
+// SetDLQDepth records the current depth of the dead letter queue
+func SetDLQDepth(depth int) {
+	DLQDepth.Set(float64(depth))
+}
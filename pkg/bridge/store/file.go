@@ -0,0 +1,135 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/client"
+)
+
+// FileStore is a Store which persists subscriptions to a single json file
+// on disk, so registrations survive a bridge restart. It wraps a
+// MemoryStore for matching and indexing, flushing the full subscription
+// list to disk after every mutation
+type FileStore struct {
+	sync.Mutex
+	path   string
+	memory *MemoryStore
+}
+
+// NewFileStore creates a FileStore persisting to path, rehydrating any
+// subscriptions already recorded there
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{path: path, memory: NewMemoryStore()}
+	if err := store.rehydrate(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *FileStore) Add(subscription *client.Subscription) error {
+	if err := s.memory.Add(subscription); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+func (s *FileStore) Remove(id string) error {
+	if err := s.memory.Remove(id); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+func (s *FileStore) Get(id string) (*client.Subscription, error) {
+	return s.memory.Get(id)
+}
+
+func (s *FileStore) List() ([]*client.Subscription, error) {
+	return s.memory.List()
+}
+
+func (s *FileStore) Match(uri, hookType string) []*client.Subscription {
+	return s.memory.Match(uri, hookType)
+}
+
+func (s *FileStore) Watch() <-chan Event {
+	return s.memory.Watch()
+}
+
+func (s *FileStore) Close() error {
+	return s.memory.Close()
+}
+
+func (s *FileStore) rehydrate() error {
+	s.Lock()
+	defer s.Unlock()
+	content, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	var subscriptions []*client.Subscription
+	if err := json.Unmarshal(content, &subscriptions); err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		if err := s.memory.Add(subscription); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// persist flushes the full subscription list to s.path. The write goes to a
+// temp file in the same directory followed by a rename, so a crash or kill
+// mid-write leaves the previous, still valid file in place rather than a
+// truncated one
+func (s *FileStore) persist() error {
+	s.Lock()
+	defer s.Unlock()
+	subscriptions, err := s.memory.List()
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(subscriptions)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
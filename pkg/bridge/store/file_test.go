@@ -0,0 +1,114 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/client"
+)
+
+func TestFileStorePersistsAcrossRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridge-store-test")
+	if err != nil {
+		t.Fatalf("unexpected error creating the temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "subscriptions.json")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating the file store: %s", err)
+	}
+	sub := newSubscription("sub-1", "^/widgets/.*$", client.PRE_EVENT)
+	if err := store.Add(sub); err != nil {
+		t.Fatalf("unexpected error adding the subscription: %s", err)
+	}
+
+	rehydrated, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error rehydrating the file store: %s", err)
+	}
+	found, err := rehydrated.Get("sub-1")
+	if err != nil {
+		t.Fatalf("expected the rehydrated store to have sub-1, got error: %s", err)
+	}
+	if found.Endpoint != sub.Endpoint {
+		t.Fatalf("expected the rehydrated subscription to match, got: %+v", found)
+	}
+}
+
+// TestFileStorePersistLeavesNoTempFiles guards the atomic write: persist()
+// must not leave its temp file behind once the rename into place succeeds
+func TestFileStorePersistLeavesNoTempFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridge-store-test")
+	if err != nil {
+		t.Fatalf("unexpected error creating the temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "subscriptions.json")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating the file store: %s", err)
+	}
+	if err := store.Add(newSubscription("sub-1", "^/widgets/.*$", client.PRE_EVENT)); err != nil {
+		t.Fatalf("unexpected error adding the subscription: %s", err)
+	}
+
+	leftover, err := filepath.Glob(filepath.Join(dir, "*.tmp*"))
+	if err != nil {
+		t.Fatalf("unexpected error globbing the store dir: %s", err)
+	}
+	if len(leftover) != 0 {
+		t.Fatalf("expected no leftover temp files after persist, found: %v", leftover)
+	}
+}
+
+func TestFileStoreFailedAddDoesNotTouchTheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridge-store-test")
+	if err != nil {
+		t.Fatalf("unexpected error creating the temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "subscriptions.json")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating the file store: %s", err)
+	}
+	if err := store.Add(newSubscription("sub-1", "^/widgets/.*$", client.PRE_EVENT)); err != nil {
+		t.Fatalf("unexpected error adding the subscription: %s", err)
+	}
+	before, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading the store file: %s", err)
+	}
+
+	bad := newSubscription("sub-2", "(unterminated", client.PRE_EVENT)
+	if err := store.Add(bad); err == nil {
+		t.Fatal("expected an error adding a subscription with an invalid uri regex")
+	}
+
+	after, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading the store file: %s", err)
+	}
+	if string(before) != string(after) {
+		t.Fatal("expected the store file to be unchanged after a failed add")
+	}
+}
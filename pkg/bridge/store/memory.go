@@ -0,0 +1,152 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/client"
+)
+
+// entry pairs a subscription with its hooks' regexes, compiled once on Add
+// rather than on every Match call
+type entry struct {
+	subscription *client.Subscription
+	hooks        []*compiledHook
+}
+
+type compiledHook struct {
+	hookType string
+	pattern  *regexp.Regexp
+}
+
+// MemoryStore is the in-memory Store, indexing subscriptions by id for
+// O(1) Add, Get and Remove. It is the default used when no other Store is
+// configured, and is not durable across restarts
+type MemoryStore struct {
+	sync.RWMutex
+	subscriptions map[string]*entry
+	watchers      []chan Event
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		subscriptions: make(map[string]*entry),
+	}
+}
+
+func (s *MemoryStore) Add(subscription *client.Subscription) error {
+	hooks, err := compileHooks(subscription)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	s.subscriptions[subscription.SubscriptionID] = &entry{subscription: subscription, hooks: hooks}
+	s.Unlock()
+	s.notify(Event{Type: EventAdded, Subscription: subscription})
+	return nil
+}
+
+func (s *MemoryStore) Remove(id string) error {
+	s.Lock()
+	found, exists := s.subscriptions[id]
+	if !exists {
+		s.Unlock()
+		return fmt.Errorf("the subscription id: %s does not exist", id)
+	}
+	delete(s.subscriptions, id)
+	s.Unlock()
+	s.notify(Event{Type: EventRemoved, Subscription: found.subscription})
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (*client.Subscription, error) {
+	s.RLock()
+	defer s.RUnlock()
+	found, exists := s.subscriptions[id]
+	if !exists {
+		return nil, fmt.Errorf("the subscription id: %s does not exist", id)
+	}
+	return found.subscription, nil
+}
+
+func (s *MemoryStore) List() ([]*client.Subscription, error) {
+	s.RLock()
+	defer s.RUnlock()
+	list := make([]*client.Subscription, 0, len(s.subscriptions))
+	for _, found := range s.subscriptions {
+		list = append(list, found.subscription)
+	}
+	return list, nil
+}
+
+func (s *MemoryStore) Match(uri, hookType string) []*client.Subscription {
+	s.RLock()
+	defer s.RUnlock()
+	matches := make([]*client.Subscription, 0)
+	for _, found := range s.subscriptions {
+		for _, hook := range found.hooks {
+			if hook.hookType == hookType && hook.pattern.MatchString(uri) {
+				matches = append(matches, found.subscription)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func (s *MemoryStore) Watch() <-chan Event {
+	ch := make(chan Event, 16)
+	s.Lock()
+	s.watchers = append(s.watchers, ch)
+	s.Unlock()
+	return ch
+}
+
+func (s *MemoryStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	for _, ch := range s.watchers {
+		close(ch)
+	}
+	s.watchers = nil
+	return nil
+}
+
+func (s *MemoryStore) notify(event Event) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, ch := range s.watchers {
+		select {
+		case ch <- event:
+		default:
+			// a slow watcher should not be able to block mutations
+		}
+	}
+}
+
+func compileHooks(subscription *client.Subscription) ([]*compiledHook, error) {
+	hooks := make([]*compiledHook, 0, len(subscription.Requests))
+	for _, hook := range subscription.Requests {
+		pattern, err := regexp.Compile(hook.URI)
+		if err != nil {
+			return nil, fmt.Errorf("the hook uri: %s is not a valid regex, error: %s", hook.URI, err)
+		}
+		hooks = append(hooks, &compiledHook{hookType: hook.HookType, pattern: pattern})
+	}
+	return hooks, nil
+}
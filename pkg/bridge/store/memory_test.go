@@ -0,0 +1,107 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"testing"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/client"
+)
+
+func newSubscription(id, uriPattern, hookType string) *client.Subscription {
+	return &client.Subscription{
+		SubscriptionID: id,
+		Endpoint:       "http://example.com/hook",
+		Requests:       []*client.HookRequest{{HookType: hookType, URI: uriPattern}},
+	}
+}
+
+func TestMemoryStoreAddGetRemove(t *testing.T) {
+	store := NewMemoryStore()
+	sub := newSubscription("sub-1", "^/widgets/.*$", client.PRE_EVENT)
+
+	if err := store.Add(sub); err != nil {
+		t.Fatalf("unexpected error adding the subscription: %s", err)
+	}
+	found, err := store.Get("sub-1")
+	if err != nil {
+		t.Fatalf("unexpected error getting the subscription: %s", err)
+	}
+	if found.SubscriptionID != "sub-1" {
+		t.Fatalf("expected to get back sub-1, got: %s", found.SubscriptionID)
+	}
+
+	if err := store.Remove("sub-1"); err != nil {
+		t.Fatalf("unexpected error removing the subscription: %s", err)
+	}
+	if _, err := store.Get("sub-1"); err == nil {
+		t.Fatal("expected an error getting a removed subscription")
+	}
+}
+
+func TestMemoryStoreRemoveUnknown(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.Remove("does-not-exist"); err == nil {
+		t.Fatal("expected an error removing an unknown subscription")
+	}
+}
+
+func TestMemoryStoreMatch(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.Add(newSubscription("sub-1", "^/widgets/.*$", client.PRE_EVENT)); err != nil {
+		t.Fatalf("unexpected error adding sub-1: %s", err)
+	}
+	if err := store.Add(newSubscription("sub-2", "^/users/.*$", client.POST_EVENT)); err != nil {
+		t.Fatalf("unexpected error adding sub-2: %s", err)
+	}
+
+	matches := store.Match("/widgets/123", client.PRE_EVENT)
+	if len(matches) != 1 || matches[0].SubscriptionID != "sub-1" {
+		t.Fatalf("expected a single match for sub-1, got: %+v", matches)
+	}
+
+	if matches := store.Match("/widgets/123", client.POST_EVENT); len(matches) != 0 {
+		t.Fatalf("expected no post event matches for /widgets/123, got: %+v", matches)
+	}
+}
+
+func TestMemoryStoreAddRejectsInvalidRegex(t *testing.T) {
+	store := NewMemoryStore()
+	sub := newSubscription("sub-1", "(unterminated", client.PRE_EVENT)
+	if err := store.Add(sub); err == nil {
+		t.Fatal("expected an error adding a subscription with an invalid uri regex")
+	}
+	if _, err := store.Get("sub-1"); err == nil {
+		t.Fatal("a subscription which failed to compile must not be indexed")
+	}
+}
+
+func TestMemoryStoreWatchNotifiesOnAddAndRemove(t *testing.T) {
+	store := NewMemoryStore()
+	events := store.Watch()
+
+	if err := store.Add(newSubscription("sub-1", "^/widgets/.*$", client.PRE_EVENT)); err != nil {
+		t.Fatalf("unexpected error adding the subscription: %s", err)
+	}
+	if event := <-events; event.Type != EventAdded {
+		t.Fatalf("expected an EventAdded, got: %v", event.Type)
+	}
+
+	if err := store.Remove("sub-1"); err != nil {
+		t.Fatalf("unexpected error removing the subscription: %s", err)
+	}
+	if event := <-events; event.Type != EventRemoved {
+		t.Fatalf("expected an EventRemoved, got: %v", event.Type)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package store provides the pluggable persistence layer for bridge
+// subscriptions, via the Store interface below. MemoryStore and FileStore
+// are the implementations provided; there is no BoltDB, BadgerDB or
+// Postgres backed Store in this package. Any other Store can be plugged in
+// through Config.Store without the bridge itself changing
+package store
+
+import (
+	"github.com/gambol99/bridge.io/pkg/bridge/client"
+)
+
+// EventType describes the kind of change a Watch Event represents
+type EventType int
+
+const (
+	EventAdded EventType = iota
+	EventRemoved
+)
+
+// Event is emitted on a Watch channel whenever a subscription is added or
+// removed, allowing a future clustered bridge to observe changes made by
+// another node sharing the same store
+type Event struct {
+	Type         EventType
+	Subscription *client.Subscription
+}
+
+// Store is implemented by anything capable of persisting bridge
+// subscriptions. Implementations are expected to be safe for concurrent use
+type Store interface {
+	// Add persists a new subscription, the SubscriptionID is expected to
+	// already be set
+	Add(subscription *client.Subscription) error
+	// Remove deletes the subscription with the given id
+	Remove(id string) error
+	// Get returns a single subscription by id
+	Get(id string) (*client.Subscription, error)
+	// List returns every subscription currently held by the store
+	List() ([]*client.Subscription, error)
+	// Match returns the subscriptions which have a hook of the given type
+	// whose uri regex matches uri, using patterns precompiled at Add time
+	Match(uri, hookType string) []*client.Subscription
+	// Watch returns a channel which emits an Event on every Add or Remove.
+	// MemoryStore and FileStore drop an Event for a watcher whose channel
+	// buffer is full rather than block the mutation
+	Watch() <-chan Event
+	// Close releases any resources held by the store
+	Close() error
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2014 Rohith All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gambol99/bridge.io/pkg/bridge/client"
+
+	"github.com/gorilla/mux"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// subscriptionView is the representation of a subscription returned by the
+// subscriptions api, augmenting the wire type with its delivery stats
+type subscriptionView struct {
+	SubscriptionID string                `json:"SubscriptionID"`
+	Endpoint       string                `json:"Endpoint"`
+	Requests       []*client.HookRequest `json:"Requests"`
+	CreatedAt      time.Time             `json:"CreatedAt"`
+	Stats          subscriptionStats     `json:"Stats"`
+}
+
+type subscriptionStats struct {
+	Delivered int64  `json:"delivered"`
+	Failed    int64  `json:"failed"`
+	LastError string `json:"last_error"`
+}
+
+func (r *BridgeAPI) viewOf(subscription *client.Subscription) subscriptionView {
+	stats := r.bridge.Stats(subscription.SubscriptionID)
+	return subscriptionView{
+		SubscriptionID: subscription.SubscriptionID,
+		Endpoint:       subscription.Endpoint,
+		Requests:       subscription.Requests,
+		CreatedAt:      subscription.CreatedAt,
+		Stats: subscriptionStats{
+			Delivered: stats.Delivered,
+			Failed:    stats.Failed,
+			LastError: stats.LastError,
+		},
+	}
+}
+
+// listSubscriptionsHandler returns the subscriptions currently registered
+// with the bridge. The optional ?uri= query param filters the list down to
+// the subscriptions which would fire for that uri, reusing the bridge's own
+// hook matching logic, on the hook type given by ?hook_type= (default pre).
+// ?hook_type= on its own, without ?uri=, does not filter the list
+func (r *BridgeAPI) listSubscriptionsHandler(w http.ResponseWriter, req *http.Request) {
+	var subscriptions []*client.Subscription
+	uri := req.URL.Query().Get("uri")
+	hookType := req.URL.Query().Get("hook_type")
+	if uri != "" {
+		if hookType == "" {
+			hookType = client.PRE_EVENT
+		}
+		subscriptions = r.bridge.Match(uri, hookType)
+	} else {
+		subscriptions = r.bridge.Subscriptions()
+	}
+
+	views := make([]subscriptionView, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		views = append(views, r.viewOf(subscription))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// getSubscriptionHandler returns a single subscription by id
+func (r *BridgeAPI) getSubscriptionHandler(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	subscription, err := r.bridge.Get(id)
+	if err != nil {
+		log.Debugf("The subscription id: %s does not exist", id)
+		http.Error(w, "the subscription does not exist", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, r.viewOf(subscription))
+}